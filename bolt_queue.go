@@ -0,0 +1,278 @@
+package goribot
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltQueueBaseKey is the first sequence number handed out in an empty
+// bucket, leaving headroom on both sides for PushInHead/Push to walk
+// away from without ever reaching 0.
+const boltQueueBaseKey = uint64(1) << 62
+
+// BoltQueue is a Queue backed by an embedded bbolt database, so a
+// crawl's pending requests survive a restart. Pop moves an entry into an
+// in-flight bucket instead of deleting it; Ack clears it from there once
+// the request completes, and Recover re-queues whatever Ack never
+// reached after an unclean stop.
+type BoltQueue struct {
+	db             *bolt.DB
+	pendingBucket  []byte
+	inflightBucket []byte
+	countBucket    []byte
+}
+
+// pendingCountKey tracks how many entries are in pendingBucket, updated
+// transactionally alongside every push/pop so Len() is an O(1) bucket
+// lookup instead of a full bucket scan.
+var pendingCountKey = []byte("pending")
+
+func getCount(tx *bolt.Tx, bucket []byte, key []byte) uint64 {
+	v := tx.Bucket(bucket).Get(key)
+	if v == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(v)
+}
+
+func addCount(tx *bolt.Tx, bucket []byte, key []byte, delta int64) error {
+	n := int64(getCount(tx, bucket, key)) + delta
+	if n < 0 {
+		n = 0
+	}
+	return tx.Bucket(bucket).Put(key, itob(uint64(n)))
+}
+
+type storedRequest struct {
+	Url      string
+	Method   Method
+	Header   http.Header
+	PostData []byte
+	Attempt  int
+}
+
+// NewBoltQueue opens (creating if necessary) a bbolt database at path
+// and uses it to back a single host's queue, named name.
+func NewBoltQueue(path, name string) (*BoltQueue, error) {
+	db, err := bolt.Open(path, 0644, nil)
+	if err != nil {
+		return nil, err
+	}
+	pending := []byte(name + ".pending")
+	inflight := []byte(name + ".inflight")
+	count := []byte(name + ".count")
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(pending); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists(inflight); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(count)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltQueue{db: db, pendingBucket: pending, inflightBucket: inflight, countBucket: count}, nil
+}
+
+var _ Queue = (*BoltQueue)(nil)
+var _ Ackable = (*BoltQueue)(nil)
+var _ Recoverable = (*BoltQueue)(nil)
+
+func encodeRequest(r *Request) ([]byte, error) {
+	return json.Marshal(storedRequest{
+		Url:      r.Url.String(),
+		Method:   r.Method,
+		Header:   r.Header,
+		PostData: r.PostData,
+		Attempt:  r.Attempt,
+	})
+}
+
+func decodeRequest(b []byte) (*Request, error) {
+	var sr storedRequest
+	if err := json.Unmarshal(b, &sr); err != nil {
+		return nil, err
+	}
+	var req *Request
+	var err error
+	if sr.Method == POST {
+		req, err = NewPostRequest(sr.Url, sr.PostData, sr.Header.Get("Content-Type"))
+	} else {
+		req, err = NewGetRequest(sr.Url)
+	}
+	if err != nil {
+		return nil, err
+	}
+	req.Header = sr.Header
+	req.Attempt = sr.Attempt
+	return req, nil
+}
+
+func (q *BoltQueue) Push(r *Request) error {
+	return q.push(r, false)
+}
+
+func (q *BoltQueue) PushInHead(r *Request) error {
+	return q.push(r, true)
+}
+
+func (q *BoltQueue) push(r *Request, head bool) error {
+	b, err := encodeRequest(r)
+	if err != nil {
+		return err
+	}
+	return q.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(q.pendingBucket)
+		var key uint64
+		switch {
+		case isEmptyBucket(bucket):
+			key = boltQueueBaseKey
+		case head:
+			key = firstKey(bucket) - 1
+		default:
+			key = lastKey(bucket) + 1
+		}
+		if err := bucket.Put(itob(key), b); err != nil {
+			return err
+		}
+		return addCount(tx, q.countBucket, pendingCountKey, 1)
+	})
+}
+
+func (q *BoltQueue) Pop() (*Request, error) {
+	var req *Request
+	err := q.db.Update(func(tx *bolt.Tx) error {
+		pending := tx.Bucket(q.pendingBucket)
+		k, v := pending.Cursor().First()
+		if k == nil {
+			return nil
+		}
+		inflight := tx.Bucket(q.inflightBucket)
+		if err := inflight.Put(k, v); err != nil {
+			return err
+		}
+		if err := pending.Delete(k); err != nil {
+			return err
+		}
+		if err := addCount(tx, q.countBucket, pendingCountKey, -1); err != nil {
+			return err
+		}
+		r, err := decodeRequest(v)
+		if err != nil {
+			return err
+		}
+		r.Meta["boltqueue.key"] = append([]byte(nil), k...)
+		req = r
+		return nil
+	})
+	return req, err
+}
+
+// Ack clears r from the in-flight bucket once it's been fully handled.
+func (q *BoltQueue) Ack(r *Request) error {
+	key, ok := r.Meta["boltqueue.key"].([]byte)
+	if !ok {
+		return nil
+	}
+	return q.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(q.inflightBucket).Delete(key)
+	})
+}
+
+// Recover moves every entry still in the in-flight bucket back to
+// pending, for whatever a previous run popped but never acknowledged.
+func (q *BoltQueue) Recover() ([]*Request, error) {
+	var reqs []*Request
+	err := q.db.Update(func(tx *bolt.Tx) error {
+		inflight := tx.Bucket(q.inflightBucket)
+		pending := tx.Bucket(q.pendingBucket)
+		var keys [][]byte
+		c := inflight.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			r, err := decodeRequest(v)
+			if err != nil {
+				return err
+			}
+			if err := pending.Put(k, v); err != nil {
+				return err
+			}
+			reqs = append(reqs, r)
+			keys = append(keys, append([]byte(nil), k...))
+		}
+		for _, k := range keys {
+			if err := inflight.Delete(k); err != nil {
+				return err
+			}
+		}
+		if len(keys) > 0 {
+			return addCount(tx, q.countBucket, pendingCountKey, int64(len(keys)))
+		}
+		return nil
+	})
+	return reqs, err
+}
+
+// IsEmpty reports whether the pending bucket has any entry at all. This
+// only walks to the first leaf page via the cursor, not the whole
+// bucket, so it stays cheap on a large, disk-spilling queue.
+func (q *BoltQueue) IsEmpty() bool {
+	empty := true
+	_ = q.db.View(func(tx *bolt.Tx) error {
+		empty = isEmptyBucket(tx.Bucket(q.pendingBucket))
+		return nil
+	})
+	return empty
+}
+
+// Len returns the pending count maintained transactionally by push/Pop,
+// rather than bolt's Bucket.Stats().KeyN, which walks every page in the
+// bucket and would make every round-robin scan and idle-poll tick in
+// HostScheduler/Spider.Run scale with queue size.
+func (q *BoltQueue) Len() int {
+	n := 0
+	_ = q.db.View(func(tx *bolt.Tx) error {
+		n = int(getCount(tx, q.countBucket, pendingCountKey))
+		return nil
+	})
+	return n
+}
+
+func (q *BoltQueue) Close() error {
+	return q.db.Close()
+}
+
+func itob(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
+
+func firstKey(b *bolt.Bucket) uint64 {
+	k, _ := b.Cursor().First()
+	if k == nil {
+		return boltQueueBaseKey
+	}
+	return binary.BigEndian.Uint64(k)
+}
+
+// isEmptyBucket reports whether b has no entries, via a single cursor
+// step to the first leaf page rather than a full-bucket scan.
+func isEmptyBucket(b *bolt.Bucket) bool {
+	k, _ := b.Cursor().First()
+	return k == nil
+}
+
+func lastKey(b *bolt.Bucket) uint64 {
+	k, _ := b.Cursor().Last()
+	if k == nil {
+		return boltQueueBaseKey
+	}
+	return binary.BigEndian.Uint64(k)
+}
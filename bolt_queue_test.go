@@ -0,0 +1,130 @@
+package goribot
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func newTestBoltQueue(t *testing.T) *BoltQueue {
+	t.Helper()
+	q, err := NewBoltQueue(filepath.Join(t.TempDir(), "queue.db"), "queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { q.Close() })
+	return q
+}
+
+func TestBoltQueuePushPopOrder(t *testing.T) {
+	q := newTestBoltQueue(t)
+
+	a, _ := NewGetRequest("https://example.com/a")
+	b, _ := NewGetRequest("https://example.com/b")
+	c, _ := NewGetRequest("https://example.com/c")
+
+	if err := q.Push(a); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Push(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.PushInHead(c); err != nil {
+		t.Fatal(err)
+	}
+
+	if q.IsEmpty() {
+		t.Fatal("queue should not be empty after three pushes")
+	}
+	if got := q.Len(); got != 3 {
+		t.Fatalf("Len() = %d, want 3", got)
+	}
+
+	want := []string{"https://example.com/c", "https://example.com/a", "https://example.com/b"}
+	for i, w := range want {
+		r, err := q.Pop()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r == nil {
+			t.Fatalf("Pop() #%d returned nil, want %q", i, w)
+		}
+		if got := r.Url.String(); got != w {
+			t.Errorf("Pop() #%d = %q, want %q", i, got, w)
+		}
+	}
+
+	if !q.IsEmpty() {
+		t.Error("queue should be empty after draining every push")
+	}
+	if got := q.Len(); got != 0 {
+		t.Errorf("Len() = %d, want 0", got)
+	}
+}
+
+func TestBoltQueueAckRemovesFromInflight(t *testing.T) {
+	q := newTestBoltQueue(t)
+
+	r, _ := NewGetRequest("https://example.com/a")
+	if err := q.Push(r); err != nil {
+		t.Fatal(err)
+	}
+	popped, err := q.Pop()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := q.Len(); got != 0 {
+		t.Fatalf("Len() after Pop = %d, want 0 (popped entries leave pending)", got)
+	}
+
+	reqs, err := q.Recover()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reqs) != 1 {
+		t.Fatalf("Recover() before Ack returned %d requests, want 1", len(reqs))
+	}
+	if got := q.Len(); got != 1 {
+		t.Fatalf("Len() after Recover = %d, want 1", got)
+	}
+
+	popped, err = q.Pop()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Ack(popped); err != nil {
+		t.Fatal(err)
+	}
+	reqs, err = q.Recover()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reqs) != 0 {
+		t.Fatalf("Recover() after Ack returned %d requests, want 0", len(reqs))
+	}
+}
+
+func TestBoltQueueLenSurvivesReopen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "queue.db")
+
+	q, err := NewBoltQueue(path, "queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _ := NewGetRequest("https://example.com/a")
+	if err := q.Push(r); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	q2, err := NewBoltQueue(path, "queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q2.Close()
+	if got := q2.Len(); got != 1 {
+		t.Fatalf("Len() after reopen = %d, want 1", got)
+	}
+}
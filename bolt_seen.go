@@ -0,0 +1,48 @@
+package goribot
+
+import bolt "go.etcd.io/bbolt"
+
+// BoltSeen is a disk-backed Seen set for crawls too large to fit a
+// reasonable bloom filter in memory, reusing the same embedded KV store
+// as BoltQueue.
+type BoltSeen struct {
+	db     *bolt.DB
+	bucket []byte
+}
+
+func NewBoltSeen(path string) (*BoltSeen, error) {
+	db, err := bolt.Open(path, 0644, nil)
+	if err != nil {
+		return nil, err
+	}
+	bucket := []byte("seen")
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltSeen{db: db, bucket: bucket}, nil
+}
+
+var _ Seen = (*BoltSeen)(nil)
+
+func (b *BoltSeen) SeenBefore(fp string) bool {
+	seen := false
+	_ = b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.bucket)
+		key := []byte(fp)
+		if bucket.Get(key) != nil {
+			seen = true
+			return nil
+		}
+		return bucket.Put(key, []byte{1})
+	})
+	return seen
+}
+
+func (b *BoltSeen) Close() error {
+	return b.db.Close()
+}
@@ -0,0 +1,124 @@
+package goribot
+
+import (
+	"container/list"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// MemoryCacheStore is a CacheStore backed by an in-process LRU, for
+// crawls that don't need to survive a restart.
+type MemoryCacheStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List
+	items      map[string]*list.Element
+}
+
+type memEntry struct {
+	key  string
+	resp *Response
+}
+
+func NewMemoryCacheStore(maxEntries int) *MemoryCacheStore {
+	if maxEntries <= 0 {
+		maxEntries = 1000
+	}
+	return &MemoryCacheStore{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      map[string]*list.Element{},
+	}
+}
+
+func (m *MemoryCacheStore) Get(key string) (*Response, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el, ok := m.items[key]
+	if !ok {
+		return nil, false
+	}
+	m.ll.MoveToFront(el)
+	return el.Value.(*memEntry).resp, true
+}
+
+func (m *MemoryCacheStore) Put(key string, resp *Response, ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if el, ok := m.items[key]; ok {
+		m.ll.MoveToFront(el)
+		el.Value.(*memEntry).resp = resp
+		return
+	}
+	el := m.ll.PushFront(&memEntry{key: key, resp: resp})
+	m.items[key] = el
+	for m.ll.Len() > m.maxEntries {
+		oldest := m.ll.Back()
+		if oldest == nil {
+			break
+		}
+		m.ll.Remove(oldest)
+		delete(m.items, oldest.Value.(*memEntry).key)
+	}
+}
+
+func (m *MemoryCacheStore) Delete(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if el, ok := m.items[key]; ok {
+		m.ll.Remove(el)
+		delete(m.items, key)
+	}
+}
+
+// DiskCacheStore is a CacheStore that persists each entry as a JSON file
+// under Dir, so a crawl can resume without re-fetching unchanged pages.
+type DiskCacheStore struct {
+	Dir string
+}
+
+func NewDiskCacheStore(dir string) *DiskCacheStore {
+	return &DiskCacheStore{Dir: dir}
+}
+
+type diskEntry struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+func (d *DiskCacheStore) path(key string) string {
+	return filepath.Join(d.Dir, key+".json")
+}
+
+func (d *DiskCacheStore) Get(key string) (*Response, bool) {
+	b, err := ioutil.ReadFile(d.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var e diskEntry
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, false
+	}
+	return &Response{StatusCode: e.StatusCode, Header: e.Header, Body: e.Body}, true
+}
+
+func (d *DiskCacheStore) Put(key string, resp *Response, ttl time.Duration) {
+	if err := os.MkdirAll(d.Dir, 0755); err != nil {
+		return
+	}
+	b, err := json.Marshal(diskEntry{StatusCode: resp.StatusCode, Header: resp.Header, Body: resp.Body})
+	if err != nil {
+		return
+	}
+	_ = ioutil.WriteFile(d.path(key), b, 0644)
+}
+
+func (d *DiskCacheStore) Delete(key string) {
+	_ = os.Remove(d.path(key))
+}
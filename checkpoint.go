@@ -0,0 +1,73 @@
+package goribot
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const hostManifestFile = "hosts.json"
+
+func writeHostManifest(dir string, hosts []string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	b, err := json.Marshal(hosts)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, hostManifestFile), b, 0644)
+}
+
+func readHostManifest(dir string) []string {
+	b, err := ioutil.ReadFile(filepath.Join(dir, hostManifestFile))
+	if err != nil {
+		return nil
+	}
+	var hosts []string
+	if err := json.Unmarshal(b, &hosts); err != nil {
+		return nil
+	}
+	return hosts
+}
+
+// discoverPersistedHosts lists the hosts with a persistent queue under
+// dir by globbing its *.db files, so recovery after an unclean stop
+// doesn't depend on a prior run having called Checkpoint.
+func discoverPersistedHosts(dir string) []string {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.db"))
+	if err != nil {
+		return nil
+	}
+	hosts := make([]string, 0, len(matches))
+	for _, m := range matches {
+		hosts = append(hosts, strings.TrimSuffix(filepath.Base(m), ".db"))
+	}
+	return hosts
+}
+
+// recoverableHosts is every host Run should try to recover on startup:
+// every host with a .db file on disk, plus anything listed in
+// hosts.json that for some reason doesn't have one yet. Discovery from
+// disk is the primary mechanism, since hosts.json is only ever written
+// by the optional, user-invoked Checkpoint and a crash or kill before
+// that call must still recover.
+func recoverableHosts(dir string) []string {
+	seen := map[string]bool{}
+	var hosts []string
+	for _, h := range discoverPersistedHosts(dir) {
+		if !seen[h] {
+			seen[h] = true
+			hosts = append(hosts, h)
+		}
+	}
+	for _, h := range readHostManifest(dir) {
+		if !seen[h] {
+			seen[h] = true
+			hosts = append(hosts, h)
+		}
+	}
+	return hosts
+}
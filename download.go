@@ -0,0 +1,42 @@
+package goribot
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+)
+
+var httpClient = &http.Client{}
+
+// DoRequest is the default Spider.Downloader: a plain net/http round
+// trip, bound to r.Ctx so it's cancelled along with the request.
+func DoRequest(r *Request) (*Response, error) {
+	ctx := r.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	req, err := http.NewRequestWithContext(ctx, string(r.Method), r.Url.String(), bytes.NewReader(r.PostData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = r.Header
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+		Request:    r,
+	}, nil
+}
@@ -1,11 +1,14 @@
 package goribot
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"math/rand"
 	"net/url"
+	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -26,37 +29,141 @@ const (
 type ResponseHandler func(r *Response)
 
 type Spider struct {
-	UserAgent      string
-	ThreadPoolSize uint
-	DepthFirst     bool
-	RandSleepRange [2]time.Duration
-	Downloader     func(*Request) (*Response, error)
+	UserAgent          string
+	ThreadPoolSize     uint
+	PerHostConcurrency uint
+	DepthFirst         bool
+	RandSleepRange     [2]time.Duration
+	Downloader         func(*Request) (*Response, error)
+	OnBadHost          BadHostHandler
 
-	pipeline     []PipelineInterface
-	taskQueue    *TaskQueue
-	taskChan     chan *Request
-	taskFinished bool
-	wg           sync.WaitGroup
+	// Seen, if set, is checked inside Crawl before a request is
+	// enqueued, so a URL already seen this crawl is silently dropped.
+	Seen Seen
 
-	workingThread int32
+	// PersistDir, if set, makes every host's queue a BoltQueue rooted at
+	// PersistDir instead of an in-memory TaskQueue, so pending work
+	// survives a restart. Before it starts dispatching, Run recovers every
+	// host with a queue file under PersistDir, plus any host listed by a
+	// prior Checkpoint.
+	PersistDir string
+
+	pipeline      []PipelineInterface
+	hostScheduler *HostScheduler
+	stats         *Stats
+	inputs        []InputPlugin
+	outputs       []OutputPlugin
+	inputsActive  int32
+	taskChan      chan *Request
+	taskFinished  bool
+	wg            sync.WaitGroup
+	ctx           context.Context
+	cancel        context.CancelFunc
+	closeIOOnce   sync.Once
+}
+
+// Stats returns a point-in-time snapshot of this Spider's counters and
+// histograms.
+func (s *Spider) Stats() Snapshot {
+	return s.stats.Snapshot()
+}
+
+// Stop cancels every in-flight request's context and stops Run from
+// dispatching any more work. It also closes every registered input and
+// output plugin that implements Close, releasing live connections (e.g.
+// a KafkaInputPlugin/KafkaOutputPlugin's broker connections) and
+// unblocking an input's Read call in progress so the goroutine draining
+// it in startInputs actually returns.
+func (s *Spider) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.taskFinished = true
+	s.closeIO()
+}
+
+// closeIO closes every registered input/output plugin that implements
+// Close, exactly once per Spider -- Run calls it again on the way out
+// for a crawl that finished on its own, in case Stop was never called.
+// Plugins that don't need teardown (e.g. NullOutputPlugin) are left
+// alone.
+func (s *Spider) closeIO() {
+	s.closeIOOnce.Do(func() {
+		for _, in := range s.inputs {
+			if c, ok := in.(interface{ Close() error }); ok {
+				if err := c.Close(); err != nil {
+					log.Println("goribot: input plugin close error:", err)
+				}
+			}
+		}
+		for _, out := range s.outputs {
+			if c, ok := out.(interface{ Close() error }); ok {
+				if err := c.Close(); err != nil {
+					log.Println("goribot: output plugin close error:", err)
+				}
+			}
+		}
+	})
 }
 
 func NewSpider() *Spider {
 	return &Spider{
-		taskQueue:      NewTaskQueue(),
-		Downloader:     DoRequest,
-		UserAgent:      UserAgent,
-		DepthFirst:     false,
-		ThreadPoolSize: ThreadPoolSize,
+		hostScheduler:      NewHostScheduler(),
+		stats:              NewStats(),
+		Downloader:         DoRequest,
+		UserAgent:          UserAgent,
+		DepthFirst:         false,
+		ThreadPoolSize:     ThreadPoolSize,
+		PerHostConcurrency: defaultPerHostConcurrency,
+	}
+}
+
+// ForceDrainHost discards every request currently queued for host,
+// letting a pipeline give up on a bad host instead of waiting out its
+// backoff window.
+func (s *Spider) ForceDrainHost(host string) int {
+	return s.hostScheduler.ForceDrainHost(host)
+}
+
+// Checkpoint snapshots enough progress for a future Run to resume: the
+// set of hosts with a persistent queue, so Run knows which ones to
+// recover. It's a no-op unless PersistDir is set.
+func (s *Spider) Checkpoint() error {
+	if s.PersistDir == "" {
+		return nil
 	}
+	return writeHostManifest(s.PersistDir, s.hostScheduler.Hosts())
 }
 
 func (s *Spider) Run() {
 	if s.ThreadPoolSize == 0 {
 		s.ThreadPoolSize = ThreadPoolSize
 	}
+	if s.PerHostConcurrency == 0 {
+		s.PerHostConcurrency = defaultPerHostConcurrency
+	}
+	s.hostScheduler.OnBadHost = s.OnBadHost
+	if s.PersistDir != "" {
+		s.hostScheduler.QueueFactory = func(host string) Queue {
+			q, err := NewBoltQueue(filepath.Join(s.PersistDir, host+".db"), "queue")
+			if err != nil {
+				log.Println("goribot: persistent queue open error:", err)
+				return NewTaskQueue()
+			}
+			return q
+		}
+		for _, host := range recoverableHosts(s.PersistDir) {
+			if n, err := s.hostScheduler.Recover(host); err != nil {
+				log.Println("goribot: recover error for", host, ":", err)
+			} else if n > 0 {
+				log.Println("goribot: recovered", n, "in-flight requests for", host)
+			}
+		}
+	}
 	s.taskFinished = false
 	s.taskChan = make(chan *Request)
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.startInputs()
 	for i := uint(0); i < s.ThreadPoolSize; i++ {
 		s.wg.Add(1)
 		go func() {
@@ -65,13 +172,27 @@ func (s *Spider) Run() {
 				select {
 				case req := <-s.taskChan:
 					func() {
-						defer func() { s.workingThread -= 1 }()
+						ctx := s.ctx
+						cancel := func() {}
+						if req.Timeout > 0 {
+							ctx, cancel = context.WithTimeout(ctx, req.Timeout)
+						}
+						req.Ctx = ctx
+						defer cancel()
+
+						start := time.Now()
 						resp, err := s.Downloader(req)
 						if err != nil {
 							log.Println("Downloader Error", err, req.Url.String())
-							s.handleOnErrorPipeline(err)
+							s.hostScheduler.Done(req, true)
+							s.hostScheduler.Ack(req)
+							s.stats.observeError(req, err)
+							s.handleOnErrorPipeline(req, err)
 							return
 						}
+						s.stats.observeResponse(resp, time.Since(start))
+						s.hostScheduler.Done(req, isBadHostStatus(resp.StatusCode))
+						s.hostScheduler.Ack(req)
 						resp = s.handleOnResponsePipeline(resp)
 						if resp == nil {
 							return
@@ -85,21 +206,30 @@ func (s *Spider) Run() {
 			}
 		}()
 	}
-	for {
-		if s.taskQueue.IsEmpty() {
-			if s.workingThread == 0 { // make sure the queue is empty and no threat is working
+	for !s.taskFinished {
+		req := s.hostScheduler.Pop(s.PerHostConcurrency)
+		if req == nil {
+			// make sure no host has pending or in-flight work, and no
+			// input plugin is still expected to produce more seeds
+			if s.hostScheduler.Pending() == 0 && atomic.LoadInt32(&s.inputsActive) == 0 {
 				break
-			} else {
-				time.Sleep(1 * time.Millisecond)
 			}
-		} else {
-			s.taskChan <- s.taskQueue.Pop()
-			s.workingThread += 1
-			randSleep(s.RandSleepRange[0], s.RandSleepRange[1])
+			time.Sleep(1 * time.Millisecond)
+			continue
 		}
+		s.stats.observeDispatch(req, s.hostScheduler.Pending())
+		s.taskChan <- req
+		randSleep(s.RandSleepRange[0], s.RandSleepRange[1])
 	}
 	s.taskFinished = true
 	s.wg.Wait()
+	s.closeIO()
+}
+
+// isBadHostStatus reports whether a response status should count against
+// its host's failure streak.
+func isBadHostStatus(code int) bool {
+	return code == 429 || code >= 500
 }
 func (s *Spider) handleResponse(response *Response) {
 	for _, h := range response.Request.Handler {
@@ -109,17 +239,19 @@ func (s *Spider) handleResponse(response *Response) {
 
 // Add a new task to the queue
 func (s *Spider) Crawl(r *Request) {
+	// Only dedup first attempts: a Retry pipeline re-enqueues the same
+	// request (same fingerprint) through Crawl, which must not be mistaken
+	// for a freshly-discovered duplicate.
+	if r.Attempt == 0 && s.Seen != nil && s.Seen.SeenBefore(fingerprint(r)) {
+		return
+	}
 	r.Header.Set("User-Agent", s.UserAgent)
 	r = s.handleOnRequestPipeline(r)
 	if r == nil {
 		return
 	}
 
-	if s.DepthFirst {
-		s.taskQueue.PushInHead(r)
-	} else {
-		s.taskQueue.Push(r)
-	}
+	s.hostScheduler.Push(r, s.DepthFirst)
 }
 func (s *Spider) Get(u string, handler ...ResponseHandler) error {
 	req, err := NewGetRequest(u)
@@ -193,18 +325,24 @@ func (s *Spider) handleOnResponsePipeline(r *Response) *Response {
 	}
 	return r
 }
-func (s *Spider) handleOnErrorPipeline(err error) {
+func (s *Spider) handleOnErrorPipeline(r *Request, err error) {
 	for _, p := range s.pipeline {
-		p.OnError(s, err)
+		p.OnError(s, r, err)
 	}
 }
 func (s *Spider) NewItem(item interface{}) {
+	s.stats.observeItem()
 	for _, p := range s.pipeline {
 		item = p.OnItem(s, item)
 		if item == nil {
 			return
 		}
 	}
+	for _, out := range s.outputs {
+		if err := out.Write(item); err != nil {
+			log.Println("Output plugin error:", err)
+		}
+	}
 }
 
 func randSleep(min, max time.Duration) {
@@ -212,4 +350,4 @@ func randSleep(min, max time.Duration) {
 		return
 	}
 	time.Sleep(time.Duration(rand.Int63n(int64(max)-int64(min)) + int64(min)))
-}
\ No newline at end of file
+}
@@ -0,0 +1,244 @@
+package goribot
+
+import (
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// BadHostHandler is invoked the moment a host crosses badHostThreshold
+// consecutive failures and is put into backoff.
+type BadHostHandler func(host string, failures int)
+
+const (
+	defaultPerHostConcurrency = uint(2)
+	badHostThreshold          = 5
+	backoffBase               = 500 * time.Millisecond
+	backoffMax                = 2 * time.Minute
+)
+
+// hostQueue is the per-host queue plus the bookkeeping needed to bound
+// its concurrency and back it off after repeated failures.
+type hostQueue struct {
+	queue    Queue
+	running  uint
+	failures int
+	bad      bool
+	nextTry  time.Time
+}
+
+// HostScheduler fans the global worker pool out across one queue per
+// hostname, so a single slow or failing domain can't starve requests to
+// every other host (the same head-of-line problem a per-destination
+// worker pool solves for a delivery system).
+type HostScheduler struct {
+	mu        sync.Mutex
+	hosts     map[string]*hostQueue
+	order     []string
+	cursor    int
+	OnBadHost BadHostHandler
+
+	// QueueFactory builds the Queue for a newly-seen host. It defaults to
+	// an in-memory TaskQueue; set it to hand out a persistent Queue (e.g.
+	// BoltQueue) instead.
+	QueueFactory func(host string) Queue
+}
+
+func NewHostScheduler() *HostScheduler {
+	return &HostScheduler{hosts: map[string]*hostQueue{}}
+}
+
+func (h *HostScheduler) newQueue(host string) Queue {
+	if h.QueueFactory != nil {
+		return h.QueueFactory(host)
+	}
+	return NewTaskQueue()
+}
+
+func (h *HostScheduler) queueFor(host string) *hostQueue {
+	hq, ok := h.hosts[host]
+	if !ok {
+		hq = &hostQueue{queue: h.newQueue(host)}
+		h.hosts[host] = hq
+		h.order = append(h.order, host)
+	}
+	return hq
+}
+
+// Push enqueues r onto its host's own queue.
+func (h *HostScheduler) Push(r *Request, depthFirst bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hq := h.queueFor(r.Url.Hostname())
+	var err error
+	if depthFirst {
+		err = hq.queue.PushInHead(r)
+	} else {
+		err = hq.queue.Push(r)
+	}
+	if err != nil {
+		log.Println("goribot: queue push error:", err)
+	}
+}
+
+// Pop round-robins across hosts and returns the next request whose host
+// is under perHostConcurrency and not currently backing off, or nil if
+// nothing is runnable right now. Every non-nil result must eventually be
+// passed to Done.
+func (h *HostScheduler) Pop(perHostConcurrency uint) *Request {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.order) == 0 {
+		return nil
+	}
+	now := time.Now()
+	for i := 0; i < len(h.order); i++ {
+		idx := (h.cursor + i) % len(h.order)
+		hq := h.hosts[h.order[idx]]
+		if hq.bad && now.Before(hq.nextTry) {
+			continue
+		}
+		if perHostConcurrency > 0 && hq.running >= perHostConcurrency {
+			continue
+		}
+		if hq.queue.IsEmpty() {
+			continue
+		}
+		req, err := hq.queue.Pop()
+		if err != nil {
+			log.Println("goribot: queue pop error:", err)
+			continue
+		}
+		if req == nil {
+			continue
+		}
+		hq.running++
+		h.cursor = (idx + 1) % len(h.order)
+		return req
+	}
+	return nil
+}
+
+// Done releases r's host's concurrency slot. failed marks the attempt as
+// a network error or 5xx/429 response, counting towards that host's
+// backoff; any other outcome resets its failure streak.
+func (h *HostScheduler) Done(r *Request, failed bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hq, ok := h.hosts[r.Url.Hostname()]
+	if !ok {
+		return
+	}
+	if hq.running > 0 {
+		hq.running--
+	}
+	if !failed {
+		hq.failures = 0
+		hq.bad = false
+		return
+	}
+	hq.failures++
+	if hq.failures < badHostThreshold {
+		return
+	}
+	wasBad := hq.bad
+	hq.bad = true
+	hq.nextTry = time.Now().Add(backoff(hq.failures))
+	if !wasBad && h.OnBadHost != nil {
+		go h.OnBadHost(r.Url.Hostname(), hq.failures)
+	}
+}
+
+// Ack clears r from its host's queue's in-flight area, for a Queue that
+// implements Ackable. Plain in-memory queues ignore this.
+func (h *HostScheduler) Ack(r *Request) {
+	h.mu.Lock()
+	hq, ok := h.hosts[r.Url.Hostname()]
+	h.mu.Unlock()
+	if !ok {
+		return
+	}
+	if ak, ok := hq.queue.(Ackable); ok {
+		if err := ak.Ack(r); err != nil {
+			log.Println("goribot: queue ack error:", err)
+		}
+	}
+}
+
+// Recover pre-creates host's queue via QueueFactory and, if it's
+// Recoverable, re-queues whatever it reports as left in-flight by a
+// previous, uncleanly stopped run. It returns how many it re-queued.
+func (h *HostScheduler) Recover(host string) (int, error) {
+	h.mu.Lock()
+	hq := h.queueFor(host)
+	h.mu.Unlock()
+	rq, ok := hq.queue.(Recoverable)
+	if !ok {
+		return 0, nil
+	}
+	reqs, err := rq.Recover()
+	if err != nil {
+		return 0, err
+	}
+	return len(reqs), nil
+}
+
+// backoff is base * 2^(failures-threshold) with jitter, capped at backoffMax.
+func backoff(failures int) time.Duration {
+	shift := uint(failures - badHostThreshold)
+	if shift > 10 {
+		shift = 10
+	}
+	d := backoffBase << shift
+	if d <= 0 || d > backoffMax {
+		d = backoffMax
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d)/2+1))
+}
+
+// ForceDrainHost discards every request currently queued for host, for a
+// pipeline that wants to give up on a bad host instead of waiting out its
+// backoff window. It returns the number of requests dropped.
+func (h *HostScheduler) ForceDrainHost(host string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hq, ok := h.hosts[host]
+	if !ok {
+		return 0
+	}
+	n := 0
+	for {
+		req, err := hq.queue.Pop()
+		if err != nil || req == nil {
+			break
+		}
+		if ak, ok := hq.queue.(Ackable); ok {
+			if err := ak.Ack(req); err != nil {
+				log.Println("goribot: queue ack error:", err)
+			}
+		}
+		n++
+	}
+	return n
+}
+
+// Pending reports how many requests are still queued or in flight across
+// all hosts.
+func (h *HostScheduler) Pending() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := 0
+	for _, host := range h.order {
+		hq := h.hosts[host]
+		n += hq.queue.Len() + int(hq.running)
+	}
+	return n
+}
+
+// Hosts returns the hostnames seen so far, in discovery order.
+func (h *HostScheduler) Hosts() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.order...)
+}
@@ -0,0 +1,280 @@
+package goribot
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CacheStore is the storage backend behind HTTPCache. Implementations
+// only need to persist whatever HTTPCache hands them; freshness is
+// computed by HTTPCache itself from the response's own headers, so a
+// store can be as simple as a bounded map.
+type CacheStore interface {
+	Get(key string) (*Response, bool)
+	Put(key string, resp *Response, ttl time.Duration)
+	Delete(key string)
+}
+
+// cacheExpiresHeader is a private header HTTPCache stamps onto the copy
+// it hands to the store, so it can tell a fresh hit from a stale one
+// without trusting the store to enforce ttl itself.
+const cacheExpiresHeader = "X-Goribot-Cache-Expires"
+
+// HTTPCache is a PipelineInterface that serves and stores responses
+// according to standard HTTP caching semantics (Cache-Control, Expires,
+// ETag/If-None-Match, Last-Modified/If-Modified-Since, Vary), so a
+// repeat crawl doesn't have to re-fetch pages that haven't changed.
+type HTTPCache struct {
+	BasePipeline
+	Store CacheStore
+}
+
+func NewHTTPCache(store CacheStore) *HTTPCache {
+	return &HTTPCache{Store: store}
+}
+
+// cacheKey identifies a request's URL regardless of which headers it
+// was sent with. A response that varies on request headers (Vary)
+// needs a second, narrower key on top of this one: see variantKey.
+func cacheKey(r *Request) string {
+	h := sha1.New()
+	h.Write([]byte(string(r.Method)))
+	h.Write([]byte("\x00"))
+	h.Write([]byte(r.Url.String()))
+	h.Write([]byte("\x00"))
+	h.Write(r.PostData)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// varyIndexKey names the CacheStore entry that records a base key's
+// Vary header, so a later request can compute the right variantKey
+// before the real response exists to read Vary off of.
+func varyIndexKey(base string) string {
+	return base + ".vary"
+}
+
+// parseVary splits a Vary header into the request header names it
+// lists, dropping "*" (which means "never reusable" and is handled by
+// treating the response as effectively uncacheable elsewhere).
+func parseVary(h http.Header) []string {
+	v := h.Get("Vary")
+	if v == "" {
+		return nil
+	}
+	var names []string
+	for _, part := range strings.Split(v, ",") {
+		part = strings.TrimSpace(part)
+		if part != "" && part != "*" {
+			names = append(names, part)
+		}
+	}
+	return names
+}
+
+// variantKey folds the vary-listed request headers into base, so two
+// requests for the same URL that differ in e.g. Accept-Encoding or
+// Accept-Language get distinct cache entries instead of one clobbering
+// the other.
+func variantKey(base string, vary []string, header http.Header) string {
+	if len(vary) == 0 {
+		return base
+	}
+	h := sha1.New()
+	h.Write([]byte(base))
+	for _, name := range vary {
+		h.Write([]byte("\x00"))
+		h.Write([]byte(strings.ToLower(name)))
+		h.Write([]byte("="))
+		h.Write([]byte(header.Get(name)))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// varyFor looks up the Vary header recorded for base by a previous
+// response, if any.
+func (c *HTTPCache) varyFor(base string) []string {
+	idx, ok := c.Store.Get(varyIndexKey(base))
+	if !ok {
+		return nil
+	}
+	return parseVary(idx.Header)
+}
+
+// OnRequest serves a fresh cached hit directly, short-circuiting the
+// network fetch, and turns a stale-but-validatable hit into a
+// conditional request so the server can answer with 304.
+func (c *HTTPCache) OnRequest(s *Spider, r *Request) *Request {
+	if r.Method != GET {
+		return r
+	}
+	base := cacheKey(r)
+	key := variantKey(base, c.varyFor(base), r.Header)
+	r.Meta["httpcache.base"] = base
+	r.Meta["httpcache.key"] = key
+
+	cached, ok := c.Store.Get(key)
+	if !ok {
+		return r
+	}
+	if isFresh(cached) {
+		hit := *cached
+		hit.Request = r
+		r.Meta["httpcache.hit"] = true
+		if out := s.handleOnResponsePipeline(&hit); out != nil {
+			s.handleResponse(out)
+		}
+		return nil
+	}
+	if etag := cached.Header.Get("ETag"); etag != "" {
+		r.Header.Set("If-None-Match", etag)
+	}
+	if lm := cached.Header.Get("Last-Modified"); lm != "" {
+		r.Header.Set("If-Modified-Since", lm)
+	}
+	r.Meta["httpcache.stale"] = cached
+	return r
+}
+
+// OnResponse stores a cacheable response and replays the cached body
+// whenever the server confirms it's unchanged with a 304.
+func (c *HTTPCache) OnResponse(s *Spider, resp *Response) *Response {
+	if resp.Request.Method != GET {
+		return resp
+	}
+	if hit, _ := resp.Request.Meta["httpcache.hit"].(bool); hit {
+		// resp is OnRequest's own replayed copy of a fresh hit, re-entering
+		// the pipeline it was handed to. It was never actually fetched, so
+		// it must not be saved again -- doing so would re-stamp
+		// cacheExpiresHeader to now+ttl on every hit and make a resource
+		// fresh forever instead of anchoring its expiry to the real fetch.
+		return resp
+	}
+	base, _ := resp.Request.Meta["httpcache.base"].(string)
+	if base == "" {
+		base = cacheKey(resp.Request)
+	}
+	key, _ := resp.Request.Meta["httpcache.key"].(string)
+	if key == "" {
+		key = base
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		cached, ok := resp.Request.Meta["httpcache.stale"].(*Response)
+		if !ok {
+			return resp
+		}
+		replayed := *cached
+		replayed.Request = resp.Request
+		replayed.StatusCode = http.StatusOK
+		c.save(base, key, &replayed)
+		return &replayed
+	}
+
+	if ttl, cacheable := cacheTTL(resp); cacheable {
+		c.save(base, key, resp)
+		_ = ttl
+	} else {
+		c.Store.Delete(key)
+	}
+	return resp
+}
+
+// save stores resp under key (the Vary-folded variant key) and, if it
+// carries a Vary header, records it under base's index so the next
+// request for this URL can compute the matching variant key before a
+// response exists to read Vary off of.
+func (c *HTTPCache) save(base, key string, resp *Response) {
+	ttl, cacheable := cacheTTL(resp)
+	if !cacheable {
+		return
+	}
+	stored := *resp
+	h := cloneHeader(resp.Header)
+	h.Set(cacheExpiresHeader, strconv.FormatInt(time.Now().Add(ttl).Unix(), 10))
+	stored.Header = h
+	c.Store.Put(key, &stored, ttl)
+	if vary := resp.Header.Get("Vary"); vary != "" {
+		c.Store.Put(varyIndexKey(base), &Response{Header: http.Header{"Vary": []string{vary}}}, ttl)
+	}
+}
+
+func isFresh(cached *Response) bool {
+	exp := cached.Header.Get(cacheExpiresHeader)
+	if exp == "" {
+		return false
+	}
+	secs, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil {
+		return false
+	}
+	return time.Now().Unix() < secs
+}
+
+// cacheTTL reads Cache-Control/Expires and reports how long resp may be
+// served from cache, and whether it should be stored at all. A
+// no-store response, or one with Vary: *, is never stored; a no-cache
+// response is stored with ttl 0 so it's always revalidated via
+// If-None-Match/If-Modified-Since before reuse.
+func cacheTTL(resp *Response) (time.Duration, bool) {
+	cc := parseCacheControl(resp.Header)
+	if _, ok := cc["no-store"]; ok {
+		return 0, false
+	}
+	for _, part := range strings.Split(resp.Header.Get("Vary"), ",") {
+		if strings.TrimSpace(part) == "*" {
+			return 0, false
+		}
+	}
+	if _, ok := cc["no-cache"]; ok {
+		return 0, true
+	}
+	if v, ok := cc["max-age"]; ok {
+		if secs, err := strconv.Atoi(v); err == nil {
+			if secs <= 0 {
+				return 0, true
+			}
+			return time.Duration(secs) * time.Second, true
+		}
+	}
+	if exp := resp.Header.Get("Expires"); exp != "" {
+		if t, err := http.ParseTime(exp); err == nil {
+			if d := time.Until(t); d > 0 {
+				return d, true
+			}
+			return 0, true
+		}
+	}
+	if resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != "" {
+		return 0, true
+	}
+	return 0, false
+}
+
+func parseCacheControl(h http.Header) map[string]string {
+	cc := map[string]string{}
+	for _, part := range strings.Split(h.Get("Cache-Control"), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) == 2 {
+			cc[strings.ToLower(kv[0])] = kv[1]
+		} else {
+			cc[strings.ToLower(kv[0])] = ""
+		}
+	}
+	return cc
+}
+
+func cloneHeader(h http.Header) http.Header {
+	out := make(http.Header, len(h))
+	for k, v := range h {
+		out[k] = append([]string(nil), v...)
+	}
+	return out
+}
@@ -0,0 +1,151 @@
+package goribot
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseCacheControl(t *testing.T) {
+	h := http.Header{}
+	h.Set("Cache-Control", "max-age=60, no-cache, Public")
+	cc := parseCacheControl(h)
+	if cc["max-age"] != "60" {
+		t.Errorf("max-age = %q, want 60", cc["max-age"])
+	}
+	if _, ok := cc["no-cache"]; !ok {
+		t.Error("no-cache not recorded")
+	}
+	if _, ok := cc["public"]; !ok {
+		t.Error("directive names should be lowercased")
+	}
+}
+
+func header(kv ...string) http.Header {
+	h := http.Header{}
+	for i := 0; i+1 < len(kv); i += 2 {
+		h.Set(kv[i], kv[i+1])
+	}
+	return h
+}
+
+func TestCacheTTL(t *testing.T) {
+	cases := []struct {
+		name      string
+		header    http.Header
+		wantTTL   time.Duration
+		wantCache bool
+	}{
+		{"no-store", header("Cache-Control", "no-store"), 0, false},
+		{"no-cache", header("Cache-Control", "no-cache"), 0, true},
+		{"max-age", header("Cache-Control", "max-age=120"), 120 * time.Second, true},
+		{"max-age-zero", header("Cache-Control", "max-age=0"), 0, true},
+		{"etag-only", header("ETag", `"abc"`), 0, true},
+		{"last-modified-only", header("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT"), 0, true},
+		{"no-directive", header(), 0, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			resp := &Response{Header: c.header}
+			ttl, cacheable := cacheTTL(resp)
+			if cacheable != c.wantCache {
+				t.Fatalf("cacheable = %v, want %v", cacheable, c.wantCache)
+			}
+			if cacheable && c.wantTTL != 0 && ttl != c.wantTTL {
+				t.Errorf("ttl = %v, want %v", ttl, c.wantTTL)
+			}
+		})
+	}
+}
+
+func TestIsFresh(t *testing.T) {
+	fresh := &Response{Header: http.Header{}}
+	fresh.Header.Set(cacheExpiresHeader, strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10))
+	if !isFresh(fresh) {
+		t.Error("expected entry expiring in the future to be fresh")
+	}
+
+	stale := &Response{Header: http.Header{}}
+	stale.Header.Set(cacheExpiresHeader, strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10))
+	if isFresh(stale) {
+		t.Error("expected entry that expired in the past to be stale")
+	}
+
+	missing := &Response{Header: http.Header{}}
+	if isFresh(missing) {
+		t.Error("expected entry with no stamped expiry to be stale")
+	}
+}
+
+func TestVariantKeyFoldsVaryHeaders(t *testing.T) {
+	base := "deadbeef"
+
+	plain := http.Header{}
+	plain.Set("Accept-Encoding", "gzip")
+	gzipKey := variantKey(base, []string{"Accept-Encoding"}, plain)
+
+	identity := http.Header{}
+	identity.Set("Accept-Encoding", "identity")
+	identityKey := variantKey(base, []string{"Accept-Encoding"}, identity)
+
+	if gzipKey == identityKey {
+		t.Error("requests differing in a Vary-listed header must not share a variant key")
+	}
+	if variantKey(base, nil, plain) != base {
+		t.Error("a response with no Vary header should key on the base key alone")
+	}
+}
+
+func TestHTTPCacheHitDoesNotExtendExpiry(t *testing.T) {
+	store := NewMemoryCacheStore(10)
+	cache := NewHTTPCache(store)
+	s := NewSpider()
+	s.Use(cache)
+
+	req, err := NewGetRequest("https://example.com/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := cacheKey(req)
+	key := variantKey(base, nil, req.Header)
+
+	stamped := &Response{StatusCode: http.StatusOK, Header: header("Cache-Control", "max-age=60")}
+	originalExpiry := strconv.FormatInt(time.Now().Add(60*time.Second).Unix(), 10)
+	stamped.Header.Set(cacheExpiresHeader, originalExpiry)
+	store.Put(key, stamped, 60*time.Second)
+
+	hit, err := NewGetRequest("https://example.com/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := cache.OnRequest(s, hit); out != nil {
+		t.Fatal("OnRequest should short-circuit a fresh cache hit, not return a request to dispatch")
+	}
+
+	cached, ok := store.Get(key)
+	if !ok {
+		t.Fatal("cache entry disappeared after a hit")
+	}
+	if got := cached.Header.Get(cacheExpiresHeader); got != originalExpiry {
+		t.Errorf("cacheExpiresHeader = %q after a hit, want unchanged %q (a hit must not slide its own expiry forward)", got, originalExpiry)
+	}
+}
+
+func TestParseVary(t *testing.T) {
+	h := http.Header{}
+	h.Set("Vary", "Accept-Encoding, Accept-Language")
+	got := parseVary(h)
+	want := []string{"Accept-Encoding", "Accept-Language"}
+	if len(got) != len(want) {
+		t.Fatalf("parseVary = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("parseVary[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+	if parseVary(http.Header{}) != nil {
+		t.Error("expected no Vary header to parse to nil")
+	}
+}
@@ -0,0 +1,79 @@
+package goribot
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+)
+
+// seedLine is the on-disk shape of one line in a FileInputPlugin's JSONL
+// seed file.
+type seedLine struct {
+	Url      string `json:"url"`
+	Method   Method `json:"method,omitempty"`
+	PostData []byte `json:"postData,omitempty"`
+}
+
+// FileInputPlugin reads seed requests from a JSONL file, one
+// {"url": "..."} object per line.
+type FileInputPlugin struct {
+	file    *os.File
+	scanner *bufio.Scanner
+}
+
+func NewFileInputPlugin(path string) (*FileInputPlugin, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &FileInputPlugin{file: f, scanner: bufio.NewScanner(f)}, nil
+}
+
+func (p *FileInputPlugin) Read() (*Request, error) {
+	if !p.scanner.Scan() {
+		p.file.Close()
+		if err := p.scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	var line seedLine
+	if err := json.Unmarshal(p.scanner.Bytes(), &line); err != nil {
+		return nil, err
+	}
+	if line.Method == POST {
+		return NewPostRequest(line.Url, line.PostData, "application/json")
+	}
+	return NewGetRequest(line.Url)
+}
+
+// FileOutputPlugin appends each item as a JSON line to a file.
+type FileOutputPlugin struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewFileOutputPlugin(path string) (*FileOutputPlugin, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileOutputPlugin{file: f}, nil
+}
+
+func (p *FileOutputPlugin) Write(item interface{}) error {
+	b, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err = p.file.Write(append(b, '\n'))
+	return err
+}
+
+func (p *FileOutputPlugin) Close() error {
+	return p.file.Close()
+}
@@ -0,0 +1,34 @@
+package goribot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// HTTPOutputPlugin POSTs each item as JSON to a webhook URL.
+type HTTPOutputPlugin struct {
+	Url    string
+	Client *http.Client
+}
+
+func NewHTTPOutputPlugin(url string) *HTTPOutputPlugin {
+	return &HTTPOutputPlugin{Url: url, Client: http.DefaultClient}
+}
+
+func (p *HTTPOutputPlugin) Write(item interface{}) error {
+	b, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	resp, err := p.Client.Post(p.Url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("goribot: webhook %s returned %d", p.Url, resp.StatusCode)
+	}
+	return nil
+}
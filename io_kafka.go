@@ -0,0 +1,70 @@
+package goribot
+
+import (
+	"context"
+	"encoding/json"
+
+	kafka "github.com/segmentio/kafka-go"
+)
+
+// KafkaInputPlugin reads seed requests (one JSON {"url": "..."} message
+// per seed) from a Kafka topic.
+type KafkaInputPlugin struct {
+	Reader *kafka.Reader
+}
+
+func NewKafkaInputPlugin(brokers []string, topic, groupID string) *KafkaInputPlugin {
+	return &KafkaInputPlugin{Reader: kafka.NewReader(kafka.ReaderConfig{
+		Brokers: brokers,
+		Topic:   topic,
+		GroupID: groupID,
+	})}
+}
+
+// Close releases the underlying broker connection. Spider calls it on
+// Stop for every registered input that implements it.
+func (p *KafkaInputPlugin) Close() error {
+	return p.Reader.Close()
+}
+
+func (p *KafkaInputPlugin) Read() (*Request, error) {
+	msg, err := p.Reader.ReadMessage(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	var line seedLine
+	if err := json.Unmarshal(msg.Value, &line); err != nil {
+		return nil, err
+	}
+	if line.Method == POST {
+		return NewPostRequest(line.Url, line.PostData, "application/json")
+	}
+	return NewGetRequest(line.Url)
+}
+
+// KafkaOutputPlugin writes each item as a JSON message to a Kafka topic.
+type KafkaOutputPlugin struct {
+	Writer *kafka.Writer
+}
+
+func NewKafkaOutputPlugin(brokers []string, topic string) *KafkaOutputPlugin {
+	return &KafkaOutputPlugin{Writer: &kafka.Writer{
+		Addr:     kafka.TCP(brokers...),
+		Topic:    topic,
+		Balancer: &kafka.LeastBytes{},
+	}}
+}
+
+// Close releases the underlying broker connection. Spider calls it on
+// Stop for every registered output that implements it.
+func (p *KafkaOutputPlugin) Close() error {
+	return p.Writer.Close()
+}
+
+func (p *KafkaOutputPlugin) Write(item interface{}) error {
+	b, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	return p.Writer.WriteMessages(context.Background(), kafka.Message{Value: b})
+}
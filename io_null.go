@@ -0,0 +1,7 @@
+package goribot
+
+// NullOutputPlugin discards every item, for benchmarking a crawl without
+// paying for real output I/O.
+type NullOutputPlugin struct{}
+
+func (NullOutputPlugin) Write(item interface{}) error { return nil }
@@ -0,0 +1,80 @@
+package goribot
+
+import (
+	"io"
+	"log"
+	"sync/atomic"
+)
+
+// InputPlugin supplies seed requests to a Spider. Read returns io.EOF
+// once the plugin is exhausted.
+type InputPlugin interface {
+	Read() (*Request, error)
+}
+
+// OutputPlugin receives every item emitted via Spider.NewItem, once the
+// pipeline chain has approved it.
+type OutputPlugin interface {
+	Write(item interface{}) error
+}
+
+// RegisterInput adds an InputPlugin that Run drains into Crawl.
+func (s *Spider) RegisterInput(p InputPlugin) {
+	s.inputs = append(s.inputs, p)
+}
+
+// RegisterOutput adds an OutputPlugin that every item emitted via
+// NewItem is written to.
+func (s *Spider) RegisterOutput(p OutputPlugin) {
+	s.outputs = append(s.outputs, p)
+}
+
+// inputResult carries one InputPlugin.Read outcome across the channel
+// startInputs selects on against s.ctx.Done(), since Read itself takes
+// no context and can't be interrupted mid-call.
+type inputResult struct {
+	req *Request
+	err error
+}
+
+// startInputs drains every registered InputPlugin concurrently, crawling
+// each seed it produces, until it reports io.EOF. Run's dispatch loop
+// tracks inputsActive so it doesn't stop early while an input is still
+// expected to produce more seeds. Each goroutine is registered with
+// s.wg and selects on s.ctx.Done(), the same way retry.go bounds its
+// own re-enqueue goroutine, so Stop()/Wait() actually bounds it instead
+// of letting it crawl forever into queues nobody drains anymore. A
+// plugin whose Read blocks past Stop (e.g. a KafkaInputPlugin with no
+// read deadline) still needs Close to unblock the call in progress; see
+// Spider.closeIO.
+func (s *Spider) startInputs() {
+	for _, in := range s.inputs {
+		atomic.AddInt32(&s.inputsActive, 1)
+		s.wg.Add(1)
+		go func(in InputPlugin) {
+			defer s.wg.Done()
+			defer atomic.AddInt32(&s.inputsActive, -1)
+			for {
+				results := make(chan inputResult, 1)
+				go func() {
+					req, err := in.Read()
+					results <- inputResult{req, err}
+				}()
+				select {
+				case <-s.ctx.Done():
+					return
+				case res := <-results:
+					if res.err != nil {
+						if res.err != io.EOF {
+							log.Println("Input plugin error:", res.err)
+						}
+						return
+					}
+					if res.req != nil {
+						s.Crawl(res.req)
+					}
+				}
+			}
+		}(in)
+	}
+}
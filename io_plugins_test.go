@@ -0,0 +1,77 @@
+package goribot
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+// blockingInput is an InputPlugin whose Read never returns until Close
+// is called, modeling a KafkaInputPlugin reading with no deadline.
+type blockingInput struct {
+	closed chan struct{}
+}
+
+func newBlockingInput() *blockingInput {
+	return &blockingInput{closed: make(chan struct{})}
+}
+
+func (b *blockingInput) Read() (*Request, error) {
+	<-b.closed
+	return nil, io.EOF
+}
+
+func (b *blockingInput) Close() error {
+	close(b.closed)
+	return nil
+}
+
+func TestStopBoundsBlockedInputAndClosesIt(t *testing.T) {
+	s := NewSpider()
+	in := newBlockingInput()
+	s.RegisterInput(in)
+
+	done := make(chan struct{})
+	go func() {
+		s.Run()
+		close(done)
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	s.Stop()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return shortly after Stop; the blocked input goroutine is unbounded")
+	}
+
+	select {
+	case <-in.closed:
+	default:
+		t.Error("Stop did not close the registered input plugin")
+	}
+}
+
+type closeTrackingOutput struct {
+	closed bool
+}
+
+func (c *closeTrackingOutput) Write(item interface{}) error { return nil }
+
+func (c *closeTrackingOutput) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestStopClosesRegisteredOutputs(t *testing.T) {
+	s := NewSpider()
+	out := &closeTrackingOutput{}
+	s.RegisterOutput(out)
+
+	s.Stop()
+
+	if !out.closed {
+		t.Error("Stop did not close a registered output plugin that implements Close")
+	}
+}
@@ -0,0 +1,21 @@
+package goribot
+
+// PipelineInterface lets a component observe and mutate requests,
+// responses, errors and items as they flow through a Spider.
+type PipelineInterface interface {
+	Init(s *Spider)
+	OnRequest(s *Spider, r *Request) *Request
+	OnResponse(s *Spider, r *Response) *Response
+	OnError(s *Spider, r *Request, err error)
+	OnItem(s *Spider, item interface{}) interface{}
+}
+
+// BasePipeline is a no-op PipelineInterface meant to be embedded so a
+// concrete pipeline only has to override the hooks it cares about.
+type BasePipeline struct{}
+
+func (BasePipeline) Init(s *Spider)                                 {}
+func (BasePipeline) OnRequest(s *Spider, r *Request) *Request       { return r }
+func (BasePipeline) OnResponse(s *Spider, r *Response) *Response    { return r }
+func (BasePipeline) OnError(s *Spider, r *Request, err error)       {}
+func (BasePipeline) OnItem(s *Spider, item interface{}) interface{} { return item }
@@ -0,0 +1,111 @@
+package goribot
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// PrometheusPipeline mirrors a Spider's activity into a user-supplied
+// *prometheus.Registry, labelling counters by host so operators can see
+// which domain is slow or erroring. If Addr is set, Init also starts a
+// "/metrics" HTTP server serving Registry.
+type PrometheusPipeline struct {
+	BasePipeline
+
+	Registry *prometheus.Registry
+	Addr     string
+
+	requests  *prometheus.CounterVec
+	responses *prometheus.CounterVec
+	errors    *prometheus.CounterVec
+	bytes     *prometheus.CounterVec
+	items     prometheus.Counter
+	latency   prometheus.Histogram
+	size      prometheus.Histogram
+	queue     prometheus.Gauge
+}
+
+func NewPrometheusPipeline(registry *prometheus.Registry) *PrometheusPipeline {
+	p := &PrometheusPipeline{
+		Registry: registry,
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "goribot_requests_total",
+			Help: "Requests seen by the pipeline on Crawl, before enqueue, by host.",
+		}, []string{"host"}),
+		responses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "goribot_responses_total",
+			Help: "Responses received, by host and status class.",
+		}, []string{"host", "class"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "goribot_errors_total",
+			Help: "Downloader errors, by host and kind.",
+		}, []string{"host", "kind"}),
+		bytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "goribot_bytes_downloaded_total",
+			Help: "Response bytes downloaded, by host.",
+		}, []string{"host"}),
+		items: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "goribot_items_total",
+			Help: "Items emitted via Spider.NewItem.",
+		}),
+		latency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "goribot_response_latency_seconds",
+			Help:    "Response latency.",
+			Buckets: prometheus.DefBuckets,
+		}),
+		size: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "goribot_response_size_bytes",
+			Help:    "Response body size.",
+			Buckets: prometheus.ExponentialBuckets(256, 4, 8),
+		}),
+		queue: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "goribot_queue_depth",
+			Help: "Requests queued or in flight across all hosts.",
+		}),
+	}
+	registry.MustRegister(p.requests, p.responses, p.errors, p.bytes, p.items, p.latency, p.size, p.queue)
+	return p
+}
+
+func (p *PrometheusPipeline) Init(s *Spider) {
+	if p.Addr == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(p.Registry, promhttp.HandlerOpts{}))
+	go http.ListenAndServe(p.Addr, mux)
+}
+
+func (p *PrometheusPipeline) OnRequest(s *Spider, r *Request) *Request {
+	p.requests.WithLabelValues(r.Url.Hostname()).Inc()
+	p.queue.Set(float64(s.hostScheduler.Pending()))
+	r.Meta["prometheus.start"] = time.Now()
+	return r
+}
+
+func (p *PrometheusPipeline) OnResponse(s *Spider, r *Response) *Response {
+	host := r.Request.Url.Hostname()
+	p.responses.WithLabelValues(host, statusClass(r.StatusCode)).Inc()
+	p.bytes.WithLabelValues(host).Add(float64(len(r.Body)))
+	p.size.Observe(float64(len(r.Body)))
+	if start, ok := r.Request.Meta["prometheus.start"].(time.Time); ok {
+		p.latency.Observe(time.Since(start).Seconds())
+	}
+	return r
+}
+
+func (p *PrometheusPipeline) OnError(s *Spider, r *Request, err error) {
+	host := ""
+	if r != nil && r.Url != nil {
+		host = r.Url.Hostname()
+	}
+	p.errors.WithLabelValues(host, errorKind(err)).Inc()
+}
+
+func (p *PrometheusPipeline) OnItem(s *Spider, item interface{}) interface{} {
+	p.items.Inc()
+	return item
+}
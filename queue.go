@@ -0,0 +1,26 @@
+package goribot
+
+// Queue is the persistence contract behind a single host's pending
+// requests. TaskQueue is the default in-memory implementation;
+// BoltQueue is a disk-backed one that survives restarts.
+type Queue interface {
+	Push(r *Request) error
+	PushInHead(r *Request) error
+	Pop() (*Request, error)
+	IsEmpty() bool
+	Len() int
+}
+
+// Recoverable is implemented by a Queue that can report requests left
+// in-flight (popped but never acknowledged) by a previous, uncleanly
+// stopped run.
+type Recoverable interface {
+	Recover() ([]*Request, error)
+}
+
+// Ackable is implemented by a Queue whose Pop moves an item into an
+// in-flight area that must be explicitly cleared once the request
+// completes, so Recover can tell a finished pop from an abandoned one.
+type Ackable interface {
+	Ack(r *Request) error
+}
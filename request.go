@@ -0,0 +1,67 @@
+package goribot
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type Method string
+
+const (
+	GET  Method = "GET"
+	POST Method = "POST"
+)
+
+// Request describes a single page fetch queued onto a Spider.
+type Request struct {
+	Url      *url.URL
+	Method   Method
+	Header   http.Header
+	PostData []byte
+	Handler  []ResponseHandler
+	Meta     map[string]interface{}
+
+	// Ctx is the context the downloader runs under. Spider.Run derives
+	// it from the spider's own context, applying Timeout if set, so
+	// Spider.Stop() or an elapsed deadline actually aborts an in-flight
+	// fetch rather than just future retries.
+	Ctx     context.Context
+	Timeout time.Duration
+
+	// Attempt counts retries: 0 on the first try, incremented each time
+	// a Retry pipeline re-enqueues the request.
+	Attempt int
+}
+
+func NewGetRequest(u string) (*Request, error) {
+	ur, err := url.Parse(u)
+	if err != nil {
+		return nil, err
+	}
+	return &Request{
+		Url:    ur,
+		Method: GET,
+		Header: http.Header{},
+		Meta:   map[string]interface{}{},
+		Ctx:    context.Background(),
+	}, nil
+}
+
+func NewPostRequest(u string, data []byte, contentType string) (*Request, error) {
+	ur, err := url.Parse(u)
+	if err != nil {
+		return nil, err
+	}
+	h := http.Header{}
+	h.Set("Content-Type", contentType)
+	return &Request{
+		Url:      ur,
+		Method:   POST,
+		Header:   h,
+		PostData: data,
+		Meta:     map[string]interface{}{},
+		Ctx:      context.Background(),
+	}, nil
+}
@@ -0,0 +1,11 @@
+package goribot
+
+import "net/http"
+
+// Response is the result of running a Request's downloader.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	Request    *Request
+}
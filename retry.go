@@ -0,0 +1,111 @@
+package goribot
+
+import (
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// RetryPredicate reports whether an attempt should be retried. err is
+// non-nil for a network/downloader failure; otherwise resp carries the
+// response that should be judged.
+type RetryPredicate func(resp *Response, err error) bool
+
+// DefaultRetryOn retries network errors and 429/5xx responses.
+func DefaultRetryOn(resp *Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp != nil && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500)
+}
+
+// Retry is a PipelineInterface that re-enqueues a failed request up to
+// MaxRetries times with exponential backoff, instead of letting the
+// crawl give up on the first error.
+type Retry struct {
+	BasePipeline
+	MaxRetries int
+	RetryOn    RetryPredicate
+	Base       time.Duration
+	Max        time.Duration
+}
+
+func NewRetry(maxRetries int) *Retry {
+	return &Retry{
+		MaxRetries: maxRetries,
+		RetryOn:    DefaultRetryOn,
+		Base:       500 * time.Millisecond,
+		Max:        30 * time.Second,
+	}
+}
+
+func (p *Retry) OnResponse(s *Spider, resp *Response) *Response {
+	if !p.retryOn()(resp, nil) {
+		return resp
+	}
+	if p.retry(s, resp.Request) {
+		return nil
+	}
+	return resp
+}
+
+func (p *Retry) OnError(s *Spider, r *Request, err error) {
+	if !p.retryOn()(nil, err) {
+		return
+	}
+	p.retry(s, r)
+}
+
+func (p *Retry) retryOn() RetryPredicate {
+	if p.RetryOn != nil {
+		return p.RetryOn
+	}
+	return DefaultRetryOn
+}
+
+// retry re-enqueues r with Attempt incremented, after sleeping out an
+// exponential backoff, and reports whether it did so; it refuses once r
+// has already been retried MaxRetries times. The backoff runs in a
+// goroutine registered with s.wg and cancelled by s.ctx, so Stop/Wait
+// actually bound it instead of leaving it to push into a queue nobody's
+// draining after Run has returned.
+func (p *Retry) retry(s *Spider, r *Request) bool {
+	if r.Attempt >= p.MaxRetries {
+		return false
+	}
+	r.Attempt++
+	s.stats.observeRetry()
+	delay := p.backoff(r.Attempt)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-s.ctx.Done():
+		case <-timer.C:
+			s.Crawl(r)
+		}
+	}()
+	return true
+}
+
+func (p *Retry) backoff(attempt int) time.Duration {
+	base := p.Base
+	if base <= 0 {
+		base = 500 * time.Millisecond
+	}
+	max := p.Max
+	if max <= 0 {
+		max = 30 * time.Second
+	}
+	shift := uint(attempt)
+	if shift > 10 {
+		shift = 10
+	}
+	d := base << shift
+	if d <= 0 || d > max {
+		d = max
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d)/2+1))
+}
@@ -0,0 +1,99 @@
+package goribot
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"hash/fnv"
+	"math"
+	"net/url"
+	"sync"
+)
+
+// Seen is a pluggable URL-fingerprint dedup filter, checked inside
+// Spider.Crawl before a request is ever enqueued.
+type Seen interface {
+	// SeenBefore records fp and reports whether it had already been
+	// recorded.
+	SeenBefore(fp string) bool
+}
+
+// fingerprint canonicalizes a request's method, URL and body into a
+// single dedup key.
+func fingerprint(r *Request) string {
+	h := sha1.New()
+	h.Write([]byte(string(r.Method)))
+	h.Write([]byte{0})
+	h.Write([]byte(canonicalizeURL(r.Url)))
+	h.Write([]byte{0})
+	h.Write(r.PostData)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// canonicalizeURL drops the fragment and sorts query parameters so that
+// equivalent URLs fingerprint the same way regardless of how they were
+// written.
+func canonicalizeURL(u *url.URL) string {
+	c := *u
+	c.Fragment = ""
+	c.RawQuery = c.Query().Encode()
+	return c.String()
+}
+
+// MemoryBloomSeen is an in-memory bloom filter Seen store, a good fit
+// for small-to-medium crawls: constant memory, no false negatives, a
+// small configurable false-positive rate.
+type MemoryBloomSeen struct {
+	mu   sync.Mutex
+	bits []uint64
+	m    uint64
+	k    uint64
+}
+
+// NewMemoryBloomSeen sizes a bloom filter for expectedItems entries at
+// roughly falsePositiveRate.
+func NewMemoryBloomSeen(expectedItems int, falsePositiveRate float64) *MemoryBloomSeen {
+	m, k := bloomParams(expectedItems, falsePositiveRate)
+	return &MemoryBloomSeen{bits: make([]uint64, (m+63)/64), m: m, k: k}
+}
+
+func bloomParams(n int, p float64) (m, k uint64) {
+	if n <= 0 {
+		n = 1000
+	}
+	if p <= 0 || p >= 1 {
+		p = 0.01
+	}
+	mf := -float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)
+	kf := mf / float64(n) * math.Ln2
+	if kf < 1 {
+		kf = 1
+	}
+	return uint64(mf) + 1, uint64(kf) + 1
+}
+
+func (b *MemoryBloomSeen) SeenBefore(fp string) bool {
+	h1, h2 := splitHash(fp)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	seen := true
+	for i := uint64(0); i < b.k; i++ {
+		idx := (h1 + i*h2) % b.m
+		if b.bits[idx/64]&(1<<(idx%64)) == 0 {
+			seen = false
+			b.bits[idx/64] |= 1 << (idx % 64)
+		}
+	}
+	return seen
+}
+
+func splitHash(s string) (uint64, uint64) {
+	h1 := fnv.New64a()
+	h1.Write([]byte(s))
+	h2 := fnv.New64()
+	h2.Write([]byte(s))
+	sum2 := h2.Sum64()
+	if sum2 == 0 {
+		sum2 = 1
+	}
+	return h1.Sum64(), sum2
+}
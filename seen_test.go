@@ -0,0 +1,78 @@
+package goribot
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMemoryBloomSeenNoFalseNegatives(t *testing.T) {
+	const n = 1000
+	s := NewMemoryBloomSeen(n, 0.01)
+	fps := make([]string, n)
+	for i := range fps {
+		fps[i] = fmt.Sprintf("fp-%d", i)
+	}
+	falsePositives := 0
+	for _, fp := range fps {
+		if s.SeenBefore(fp) {
+			// A bloom filter trades false negatives for false positives:
+			// at p=0.01 a small fraction of first-time lookups may
+			// already collide with bits set by earlier entries.
+			falsePositives++
+		}
+	}
+	if falsePositives > n/10 {
+		t.Fatalf("false positive rate too high: %d/%d first-time lookups reported seen", falsePositives, n)
+	}
+	for _, fp := range fps {
+		if !s.SeenBefore(fp) {
+			t.Fatalf("%q not reported as seen on its second lookup (false negative)", fp)
+		}
+	}
+}
+
+func TestBloomParamsClampsDegenerateInput(t *testing.T) {
+	m, k := bloomParams(0, 0)
+	if m == 0 || k == 0 {
+		t.Fatalf("bloomParams(0, 0) = (%d, %d), want positive defaults", m, k)
+	}
+	m, k = bloomParams(-5, 1.5)
+	if m == 0 || k == 0 {
+		t.Fatalf("bloomParams(-5, 1.5) = (%d, %d), want positive defaults", m, k)
+	}
+}
+
+func TestCanonicalizeURLDropsFragmentAndSortsQuery(t *testing.T) {
+	r1, err := NewGetRequest("https://example.com/a?b=2&a=1#frag")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, err := NewGetRequest("https://example.com/a?a=1&b=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if canonicalizeURL(r1.Url) != canonicalizeURL(r2.Url) {
+		t.Errorf("canonicalizeURL(%q) != canonicalizeURL(%q)", r1.Url, r2.Url)
+	}
+}
+
+func TestFingerprintDiffersByMethodAndBody(t *testing.T) {
+	get, err := NewGetRequest("https://example.com/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	post1, err := NewPostRequest("https://example.com/a", []byte("x"), "text/plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	post2, err := NewPostRequest("https://example.com/a", []byte("y"), "text/plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fingerprint(get) == fingerprint(post1) {
+		t.Error("GET and POST to the same URL must not share a fingerprint")
+	}
+	if fingerprint(post1) == fingerprint(post2) {
+		t.Error("POSTs with different bodies must not share a fingerprint")
+	}
+}
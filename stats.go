@@ -0,0 +1,190 @@
+package goribot
+
+import (
+	"net/url"
+	"sync"
+	"time"
+)
+
+// HostSnapshot is the per-host slice of a Snapshot, so operators can
+// see which domain is slow or erroring.
+type HostSnapshot struct {
+	RequestsIssued   int64
+	ResponsesByClass map[string]int64
+	BytesDownloaded  int64
+	ErrorsByKind     map[string]int64
+}
+
+// Snapshot is a point-in-time, lock-free copy of a Spider's Stats.
+type Snapshot struct {
+	RequestsIssued   int64
+	ResponsesByClass map[string]int64 // "2xx", "3xx", "4xx", "5xx", "other"
+	BytesDownloaded  int64
+	ItemsEmitted     int64
+	ErrorsByKind     map[string]int64
+	Retries          int64
+
+	LatencySamples []time.Duration
+	SizeSamples    []int64
+	QueueDepth     []int64
+
+	PerHost map[string]*HostSnapshot
+}
+
+type hostStats struct {
+	requestsIssued   int64
+	responsesByClass map[string]int64
+	bytesDownloaded  int64
+	errorsByKind     map[string]int64
+}
+
+func newHostStats() *hostStats {
+	return &hostStats{responsesByClass: map[string]int64{}, errorsByKind: map[string]int64{}}
+}
+
+// Stats atomically tracks request/response/error/item counters and a
+// handful of histograms for a Spider, sampled at every pipeline stage.
+// Every Spider owns one; read it with Spider.Stats().
+type Stats struct {
+	mu               sync.Mutex
+	requestsIssued   int64
+	responsesByClass map[string]int64
+	bytesDownloaded  int64
+	itemsEmitted     int64
+	errorsByKind     map[string]int64
+	retries          int64
+	latencies        []time.Duration
+	sizes            []int64
+	queueDepths      []int64
+	perHost          map[string]*hostStats
+}
+
+func NewStats() *Stats {
+	return &Stats{
+		responsesByClass: map[string]int64{},
+		errorsByKind:     map[string]int64{},
+		perHost:          map[string]*hostStats{},
+	}
+}
+
+func (st *Stats) hostFor(host string) *hostStats {
+	hs, ok := st.perHost[host]
+	if !ok {
+		hs = newHostStats()
+		st.perHost[host] = hs
+	}
+	return hs
+}
+
+// observeDispatch records a request being handed to a worker, along with
+// the queue depth at that moment.
+func (st *Stats) observeDispatch(r *Request, queueDepth int) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.requestsIssued++
+	st.hostFor(hostOf(r.Url)).requestsIssued++
+	st.queueDepths = append(st.queueDepths, int64(queueDepth))
+}
+
+func (st *Stats) observeResponse(r *Response, latency time.Duration) {
+	class := statusClass(r.StatusCode)
+	size := int64(len(r.Body))
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.responsesByClass[class]++
+	st.bytesDownloaded += size
+	hs := st.hostFor(hostOf(r.Request.Url))
+	hs.responsesByClass[class]++
+	hs.bytesDownloaded += size
+	st.sizes = append(st.sizes, size)
+	st.latencies = append(st.latencies, latency)
+}
+
+func (st *Stats) observeError(r *Request, err error) {
+	kind := errorKind(err)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.errorsByKind[kind]++
+	if r != nil && r.Url != nil {
+		st.hostFor(hostOf(r.Url)).errorsByKind[kind]++
+	}
+}
+
+func (st *Stats) observeItem() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.itemsEmitted++
+}
+
+func (st *Stats) observeRetry() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.retries++
+}
+
+// Snapshot returns a consistent point-in-time copy of the tracked stats.
+func (st *Stats) Snapshot() Snapshot {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	snap := Snapshot{
+		RequestsIssued:   st.requestsIssued,
+		ResponsesByClass: cloneCounts(st.responsesByClass),
+		BytesDownloaded:  st.bytesDownloaded,
+		ItemsEmitted:     st.itemsEmitted,
+		ErrorsByKind:     cloneCounts(st.errorsByKind),
+		Retries:          st.retries,
+		LatencySamples:   append([]time.Duration(nil), st.latencies...),
+		SizeSamples:      append([]int64(nil), st.sizes...),
+		QueueDepth:       append([]int64(nil), st.queueDepths...),
+		PerHost:          map[string]*HostSnapshot{},
+	}
+	for host, hs := range st.perHost {
+		snap.PerHost[host] = &HostSnapshot{
+			RequestsIssued:   hs.requestsIssued,
+			ResponsesByClass: cloneCounts(hs.responsesByClass),
+			BytesDownloaded:  hs.bytesDownloaded,
+			ErrorsByKind:     cloneCounts(hs.errorsByKind),
+		}
+	}
+	return snap
+}
+
+func cloneCounts(m map[string]int64) map[string]int64 {
+	out := make(map[string]int64, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+func hostOf(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+func statusClass(code int) string {
+	switch {
+	case code >= 200 && code < 300:
+		return "2xx"
+	case code >= 300 && code < 400:
+		return "3xx"
+	case code >= 400 && code < 500:
+		return "4xx"
+	case code >= 500:
+		return "5xx"
+	default:
+		return "other"
+	}
+}
+
+func errorKind(err error) string {
+	if err == nil {
+		return "unknown"
+	}
+	if te, ok := err.(interface{ Timeout() bool }); ok && te.Timeout() {
+		return "timeout"
+	}
+	return "network"
+}
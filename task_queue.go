@@ -0,0 +1,54 @@
+package goribot
+
+import "sync"
+
+// TaskQueue is a thread-safe, in-memory double-ended Queue of pending
+// requests. It's the default per-host queue; it doesn't survive a
+// restart, unlike BoltQueue.
+type TaskQueue struct {
+	mu   sync.Mutex
+	data []*Request
+}
+
+var _ Queue = (*TaskQueue)(nil)
+
+func NewTaskQueue() *TaskQueue {
+	return &TaskQueue{}
+}
+
+func (q *TaskQueue) Push(r *Request) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.data = append(q.data, r)
+	return nil
+}
+
+func (q *TaskQueue) PushInHead(r *Request) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.data = append([]*Request{r}, q.data...)
+	return nil
+}
+
+func (q *TaskQueue) Pop() (*Request, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.data) == 0 {
+		return nil, nil
+	}
+	r := q.data[0]
+	q.data = q.data[1:]
+	return r, nil
+}
+
+func (q *TaskQueue) IsEmpty() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.data) == 0
+}
+
+func (q *TaskQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.data)
+}